@@ -1,12 +1,16 @@
 package fingerprint
 
+//go:generate go run ./hack/generate-ec2-instance-types env_aws_instance_types_generated.go
+
 import (
 	"fmt"
 	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -32,7 +36,10 @@ const (
 
 // map of instance type to approximate speed, in Mbits/s
 // Estimates from http://stackoverflow.com/a/35806587
-// This data is meant for a loose approximation
+// This data is meant for a loose approximation, and is only consulted for
+// instance types missing from the generated ec2InstanceTypeTable (see
+// env_aws_instance_types_generated.go), or whose entry there yields no
+// bandwidth figure.
 var ec2NetSpeedTable = map[*regexp.Regexp]int{
 	regexp.MustCompile("t2.nano"):      30,
 	regexp.MustCompile("t2.micro"):     70,
@@ -60,6 +67,10 @@ var ec2NetSpeedTable = map[*regexp.Regexp]int{
 // In a few cases, AWS has upgraded the generation of CPU while keeping the same
 // instance designation. Since it is possible to launch on the lower performance
 // CPU, that one is used as the spec for the instance type.
+//
+// Like ec2NetSpeedTable above, this table is only consulted for instance
+// types missing from the generated ec2InstanceTypeTable, or whose entry there
+// has a zero SustainedClockSpeedInGhz.
 var ec2ProcSpeedTable = map[*regexp.Regexp]float64{
 	// General Purpose
 	regexp.MustCompile(`a1\..*`):                              2_300, // Custom built AWS Graviton
@@ -114,6 +125,15 @@ type EnvAWSFingerprint struct {
 	endpoint string
 
 	logger log.Logger
+
+	// metaClientMu guards metaClient, which is built lazily and reused
+	// across Fingerprint calls so that the IMDSv2-vs-v1 determination
+	// (and its associated metadata-service round trips) only happens once
+	// per configured mode instead of on every fingerprint cycle. It's
+	// rebuilt if fingerprint.aws.imds_version changes between calls.
+	metaClientMu   sync.Mutex
+	metaClient     *ec2MetadataClient
+	metaClientMode imdsMode
 }
 
 // NewEnvAWSFingerprint is used to create a fingerprint from AWS metadata
@@ -135,7 +155,9 @@ func (f *EnvAWSFingerprint) Fingerprint(request *FingerprintRequest, response *F
 		timeout = 1 * time.Millisecond
 	}
 
-	ec2meta, err := ec2MetaClient(f.endpoint, timeout)
+	mode := parseIMDSMode(cfg.ReadStringDefault(AwsIMDSVersionConfig, string(imdsModeAuto)))
+
+	ec2meta, err := f.ec2MetaClient(timeout, mode)
 	if err != nil {
 		return fmt.Errorf("failed to setup ec2Metadata client: %v", err)
 	}
@@ -208,13 +230,35 @@ func (f *EnvAWSFingerprint) Fingerprint(request *FingerprintRequest, response *F
 	}
 
 	// copy over CPU speed information
-	//
-	// todo: also need num cores, cpu name, total ticks (see fingerprint.cpu)
-	if mhz := f.frequencyMHz(ec2meta); mhz != deferProcSpeed {
+	mhz := f.frequencyMHz(ec2meta)
+	if mhz != deferProcSpeed {
 		response.AddAttribute("cpu.frequency", fmt.Sprintf("%.0f", mhz))
 		f.logger.Debug("detected ec2 cpu frequency", "MHz", log.Fmt("%.0f", mhz))
 	}
 
+	// copy over vCPU count, total compute, memory, and network bandwidth, all
+	// of which are only known for instance types present in the generated
+	// ec2InstanceTypeTable
+	if info, ok := f.instanceTypeInfo(ec2meta); ok {
+		if info.DefaultVCpus > 0 {
+			response.AddAttribute("cpu.numcores", fmt.Sprintf("%d", info.DefaultVCpus))
+
+			if mhz != deferProcSpeed {
+				totalCompute := mhz * float64(info.DefaultVCpus)
+				response.AddAttribute("cpu.totalcompute", fmt.Sprintf("%.0f", totalCompute))
+			}
+		}
+
+		if info.MemoryInMiB > 0 {
+			response.AddAttribute("memory.totalbytes", fmt.Sprintf("%d", uint64(info.MemoryInMiB)*1024*1024))
+		}
+
+		if info.BaselineBandwidthInMbps > 0 {
+			response.AddAttribute("platform.aws.network.baseline-mbits", strconv.Itoa(info.BaselineBandwidthInMbps))
+			response.AddAttribute("platform.aws.network.burst-mbits", strconv.Itoa(f.burstBandwidthMbps(info)))
+		}
+	}
+
 	// populate Links
 	response.AddLink("aws.ec2", fmt.Sprintf("%s.%s",
 		response.Attributes["platform.aws.placement.availability-zone"],
@@ -224,7 +268,7 @@ func (f *EnvAWSFingerprint) Fingerprint(request *FingerprintRequest, response *F
 	return nil
 }
 
-func (f *EnvAWSFingerprint) instanceType(ec2meta *ec2metadata.EC2Metadata) (string, error) {
+func (f *EnvAWSFingerprint) instanceType(ec2meta *ec2MetadataClient) (string, error) {
 	response, err := ec2meta.GetMetadata("instance-type")
 	if err != nil {
 		return "", err
@@ -232,12 +276,17 @@ func (f *EnvAWSFingerprint) instanceType(ec2meta *ec2metadata.EC2Metadata) (stri
 	return strings.TrimSpace(response), nil
 }
 
-func (f *EnvAWSFingerprint) frequencyMHz(ec2meta *ec2metadata.EC2Metadata) float64 {
+func (f *EnvAWSFingerprint) frequencyMHz(ec2meta *ec2MetadataClient) float64 {
 	instanceType, err := f.instanceType(ec2meta)
 	if err != nil {
 		f.logger.Error("error reading instance-type", "error", err)
 		return deferProcSpeed
 	}
+
+	if info, ok := ec2InstanceTypeTable[instanceType]; ok && info.SustainedClockSpeedInGhz != 0 {
+		return info.SustainedClockSpeedInGhz * 1000
+	}
+
 	for regex, mhz := range ec2ProcSpeedTable {
 		if regex.MatchString(instanceType) {
 			return mhz
@@ -246,7 +295,50 @@ func (f *EnvAWSFingerprint) frequencyMHz(ec2meta *ec2metadata.EC2Metadata) float
 	return deferProcSpeed
 }
 
-func (f *EnvAWSFingerprint) throughput(request *FingerprintRequest, ec2meta *ec2metadata.EC2Metadata, ip string) int {
+// instanceTypeInfo looks up the current instance type in the generated
+// ec2InstanceTypeTable. Unlike frequencyMHz and linkSpeed, there is no
+// regex-table fallback here: vCPU count, memory size, and network bandwidth
+// are only known precisely for instance types the generator has seen.
+func (f *EnvAWSFingerprint) instanceTypeInfo(ec2meta *ec2MetadataClient) (ec2InstanceInfo, bool) {
+	instanceType, err := f.instanceType(ec2meta)
+	if err != nil {
+		f.logger.Error("error reading instance-type", "error", err)
+		return ec2InstanceInfo{}, false
+	}
+
+	info, ok := ec2InstanceTypeTable[instanceType]
+	return info, ok
+}
+
+// networkPerformanceMbps matches the free-text NetworkPerformance field
+// DescribeInstanceTypes reports, e.g. "Up to 10 Gigabit" or "25 Gigabit".
+var networkPerformanceMbps = regexp.MustCompile(`([\d.]+)\s*Gigabit`)
+
+// burstBandwidthMbps returns the peak (burst) network bandwidth for an
+// instance type, derived from its NetworkPerformance string. For instance
+// types without a parseable peak (or where the peak is smaller than the
+// baseline, which shouldn't happen but would indicate bad data), the
+// baseline bandwidth is used instead so burst is never reported lower than
+// baseline.
+func (f *EnvAWSFingerprint) burstBandwidthMbps(info ec2InstanceInfo) int {
+	matches := networkPerformanceMbps.FindStringSubmatch(info.NetworkPerformance)
+	if len(matches) != 2 {
+		return info.BaselineBandwidthInMbps
+	}
+
+	gbps, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return info.BaselineBandwidthInMbps
+	}
+
+	burst := int(gbps * 1000)
+	if burst < info.BaselineBandwidthInMbps {
+		return info.BaselineBandwidthInMbps
+	}
+	return burst
+}
+
+func (f *EnvAWSFingerprint) throughput(request *FingerprintRequest, ec2meta *ec2MetadataClient, ip string) int {
 	throughput := request.Config.NetworkSpeed
 	if throughput != 0 {
 		return throughput
@@ -269,13 +361,19 @@ func (f *EnvAWSFingerprint) throughput(request *FingerprintRequest, ec2meta *ec2
 }
 
 // EnvAWSFingerprint uses lookup table to approximate network speeds
-func (f *EnvAWSFingerprint) linkSpeed(ec2meta *ec2metadata.EC2Metadata) int {
+func (f *EnvAWSFingerprint) linkSpeed(ec2meta *ec2MetadataClient) int {
 	instanceType, err := f.instanceType(ec2meta)
 	if err != nil {
 		f.logger.Error("error reading instance-type", "error", err)
 		return 0
 	}
 
+	if info, ok := ec2InstanceTypeTable[instanceType]; ok {
+		if burst := f.burstBandwidthMbps(info); burst != 0 {
+			return burst
+		}
+	}
+
 	netSpeed := 0
 	for reg, speed := range ec2NetSpeedTable {
 		if reg.MatchString(instanceType) {
@@ -287,7 +385,30 @@ func (f *EnvAWSFingerprint) linkSpeed(ec2meta *ec2metadata.EC2Metadata) int {
 	return netSpeed
 }
 
-func ec2MetaClient(endpoint string, timeout time.Duration) (*ec2metadata.EC2Metadata, error) {
+// ec2MetaClient returns this fingerprinter's metadata client, building it on
+// first use and reusing it thereafter so that the IMDSv2-vs-v1 fallback
+// decision is only made once per configured mode. If fingerprint.aws.imds_version
+// changes between Fingerprint calls, the cached client is rebuilt so the new
+// mode actually takes effect.
+func (f *EnvAWSFingerprint) ec2MetaClient(timeout time.Duration, mode imdsMode) (*ec2MetadataClient, error) {
+	f.metaClientMu.Lock()
+	defer f.metaClientMu.Unlock()
+
+	if f.metaClient != nil && f.metaClientMode == mode {
+		return f.metaClient, nil
+	}
+
+	client, err := newEC2MetaClient(f.endpoint, timeout, mode, f.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	f.metaClient = client
+	f.metaClientMode = mode
+	return client, nil
+}
+
+func newEC2MetaClient(endpoint string, timeout time.Duration, mode imdsMode, logger log.Logger) (*ec2MetadataClient, error) {
 	client := &http.Client{
 		Timeout:   timeout,
 		Transport: cleanhttp.DefaultTransport(),
@@ -302,10 +423,12 @@ func ec2MetaClient(endpoint string, timeout time.Duration) (*ec2metadata.EC2Meta
 	if err != nil {
 		return nil, err
 	}
-	return ec2metadata.New(sess, c), nil
+
+	v1 := ec2metadata.New(sess, c)
+	return newEC2MetadataClient(v1, client, endpoint, mode, logger), nil
 }
 
-func isAWS(ec2meta *ec2metadata.EC2Metadata) bool {
+func isAWS(ec2meta *ec2MetadataClient) bool {
 	v, err := ec2meta.GetMetadata("ami-id")
 	v = strings.TrimSpace(v)
 	return err == nil && v != ""
@@ -0,0 +1,252 @@
+package fingerprint
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/ec2metadata"
+	log "github.com/hashicorp/go-hclog"
+)
+
+// AwsIMDSVersionConfig is the client config key operators use to pin the
+// EC2 metadata service version the AWS fingerprinter talks to. Valid values
+// are "v1", "v2", and "auto" (the default).
+const AwsIMDSVersionConfig = "fingerprint.aws.imds_version"
+
+// imdsTokenTTL is the TTL requested for IMDSv2 session tokens. The token is
+// cached for this long and transparently refreshed, either once it expires
+// or after a request is rejected with 401 Unauthorized.
+const imdsTokenTTL = 6 * time.Hour
+
+const (
+	imdsTokenPath       = "/api/token"
+	imdsTokenTTLHeader  = "X-aws-ec2-metadata-token-ttl-seconds"
+	imdsTokenAuthHeader = "X-aws-ec2-metadata-token"
+)
+
+// imdsMode is the resolved form of the fingerprint.aws.imds_version config
+// value.
+type imdsMode string
+
+const (
+	imdsModeV1   imdsMode = "v1"
+	imdsModeV2   imdsMode = "v2"
+	imdsModeAuto imdsMode = "auto"
+)
+
+func parseIMDSMode(raw string) imdsMode {
+	switch imdsMode(strings.ToLower(strings.TrimSpace(raw))) {
+	case imdsModeV1:
+		return imdsModeV1
+	case imdsModeV2:
+		return imdsModeV2
+	default:
+		return imdsModeAuto
+	}
+}
+
+// ec2MetadataClient fronts an *ec2metadata.EC2Metadata with the IMDSv2
+// token handshake (PUT /latest/api/token, then X-aws-ec2-metadata-token on
+// every subsequent GET). It falls back to IMDSv1 requests, issued directly
+// through the wrapped client, whenever v2 is unavailable or not requested.
+//
+// All callers in this package, including isAWS, should go through this
+// client rather than talking to ec2metadata.EC2Metadata directly, so that
+// IMDSv2 is used transparently wherever it's available.
+type ec2MetadataClient struct {
+	logger log.Logger
+
+	// v1 is the classic metadata client, used both as the IMDSv1 transport
+	// and as the fallback when IMDSv2 isn't reachable.
+	v1 *ec2metadata.EC2Metadata
+
+	// httpClient and tokenEndpoint are used to perform the IMDSv2 token
+	// handshake, which ec2metadata.EC2Metadata has no support for.
+	httpClient    *http.Client
+	tokenEndpoint string
+
+	mode imdsMode
+
+	mu         sync.Mutex
+	token      string
+	tokenExp   time.Time
+	v2Disabled bool // sticky once auto mode determines the token endpoint isn't available
+}
+
+// newEC2MetadataClient builds a token-aware metadata client. endpoint is the
+// same "https://host/latest"-style base used to construct v1, or "" for the
+// default IMDS endpoint.
+func newEC2MetadataClient(v1 *ec2metadata.EC2Metadata, httpClient *http.Client, endpoint string, mode imdsMode, logger log.Logger) *ec2MetadataClient {
+	if endpoint == "" {
+		endpoint = "http://169.254.169.254/latest"
+	}
+
+	return &ec2MetadataClient{
+		logger:        logger,
+		v1:            v1,
+		httpClient:    httpClient,
+		tokenEndpoint: endpoint,
+		mode:          mode,
+	}
+}
+
+// GetMetadata fetches a single EC2 metadata path (e.g. "instance-type"),
+// preferring IMDSv2 and transparently falling back to IMDSv1 in "auto" mode.
+func (c *ec2MetadataClient) GetMetadata(p string) (string, error) {
+	if c.mode == imdsModeV1 || c.usingV1Fallback() {
+		return c.v1.GetMetadata(p)
+	}
+
+	token, err := c.ensureToken()
+	if err != nil {
+		if c.mode == imdsModeV2 {
+			return "", err
+		}
+
+		if err == errIMDSTokenUnsupported {
+			c.logger.Debug("IMDSv2 not supported by this host, falling back to IMDSv1")
+			c.disableV2()
+		} else {
+			c.logger.Debug("IMDSv2 token request failed, falling back to IMDSv1 for this request", "error", err)
+		}
+		return c.v1.GetMetadata(p)
+	}
+
+	resp, err := c.getWithToken(p, token)
+	if err == errIMDSTokenExpired {
+		// The cached token was rejected; refresh once and retry before
+		// giving up.
+		c.invalidateToken()
+
+		token, err = c.ensureToken()
+		if err != nil {
+			if c.mode == imdsModeV2 {
+				return "", err
+			}
+			if err == errIMDSTokenUnsupported {
+				c.disableV2()
+			}
+			return c.v1.GetMetadata(p)
+		}
+
+		resp, err = c.getWithToken(p, token)
+	}
+
+	if err != nil && c.mode != imdsModeV2 {
+		// Don't let a transient v2 failure (timeout, 5xx, etc.) take down
+		// the whole fingerprint; a plain v1 request may still succeed.
+		c.logger.Debug("IMDSv2 request failed, falling back to IMDSv1", "path", p, "error", err)
+		return c.v1.GetMetadata(p)
+	}
+
+	return resp, err
+}
+
+func (c *ec2MetadataClient) usingV1Fallback() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.v2Disabled
+}
+
+func (c *ec2MetadataClient) disableV2() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.v2Disabled = true
+}
+
+func (c *ec2MetadataClient) invalidateToken() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
+
+// ensureToken returns the cached IMDSv2 token, fetching (or refreshing) one
+// if needed.
+func (c *ec2MetadataClient) ensureToken() (string, error) {
+	c.mu.Lock()
+	if c.token != "" && time.Now().Before(c.tokenExp) {
+		token := c.token
+		c.mu.Unlock()
+		return token, nil
+	}
+	c.mu.Unlock()
+
+	req, err := http.NewRequest(http.MethodPut, c.tokenEndpoint+imdsTokenPath, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(imdsTokenTTLHeader, fmt.Sprintf("%.0f", imdsTokenTTL.Seconds()))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+
+		token := strings.TrimSpace(string(body))
+
+		c.mu.Lock()
+		c.token = token
+		c.tokenExp = time.Now().Add(imdsTokenTTL)
+		c.mu.Unlock()
+
+		return token, nil
+	case http.StatusForbidden, http.StatusNotFound:
+		// These are the only responses that mean "this host doesn't speak
+		// IMDSv2" rather than "v2 is temporarily unreachable".
+		return "", errIMDSTokenUnsupported
+	default:
+		return "", fmt.Errorf("unexpected status fetching IMDSv2 token: %d", resp.StatusCode)
+	}
+}
+
+// errIMDSTokenUnsupported is returned by ensureToken when the token endpoint
+// responds 403/404, meaning this host doesn't support IMDSv2 at all. Unlike
+// other token-fetch errors (timeouts, 5xxs, connection failures), which are
+// treated as transient, this one sticks in "auto" mode: GetMetadata stops
+// attempting v2 for the lifetime of the client. In "v2" mode it is returned
+// to the caller and the token endpoint is tried again on the next call.
+var errIMDSTokenUnsupported = fmt.Errorf("IMDSv2 token endpoint not available")
+
+// errIMDSTokenExpired is a sentinel returned by getWithToken when the
+// metadata service rejects a token, so GetMetadata knows to refresh and
+// retry rather than surface the error to the caller.
+var errIMDSTokenExpired = fmt.Errorf("IMDSv2 token rejected by metadata service")
+
+func (c *ec2MetadataClient) getWithToken(p string, token string) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, c.tokenEndpoint+"/meta-data/"+p, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set(imdsTokenAuthHeader, token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		body, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return "", err
+		}
+		return string(body), nil
+	case http.StatusUnauthorized:
+		return "", errIMDSTokenExpired
+	default:
+		return "", fmt.Errorf("unexpected status fetching %q: %d", p, resp.StatusCode)
+	}
+}
@@ -0,0 +1,36 @@
+package fingerprint
+
+// ec2InstanceInfo holds the subset of EC2 DescribeInstanceTypes fields that
+// the AWS fingerprinter cares about. Values come only from the generated
+// ec2InstanceTypeTable (see env_aws_instance_types_generated.go, produced by
+// client/fingerprint/hack/generate-ec2-instance-types). For instance types
+// the generator hasn't seen, no ec2InstanceInfo exists; clock speed and link
+// speed then fall back to the hand-maintained ec2ProcSpeedTable /
+// ec2NetSpeedTable regex tables in env_aws.go.
+type ec2InstanceInfo struct {
+	// SustainedClockSpeedInGhz is the documented sustained all-core clock
+	// speed for the instance type's CPU, in GHz.
+	SustainedClockSpeedInGhz float64
+
+	// DefaultVCpus is the default number of vCPUs for the instance type.
+	DefaultVCpus int
+
+	// DefaultCores is the default number of physical cores for the
+	// instance type.
+	DefaultCores int
+
+	// NetworkPerformance is the free-text network performance tier AWS
+	// reports for the instance type, e.g. "Up to 10 Gigabit".
+	NetworkPerformance string
+
+	// BaselineBandwidthInMbps is the sustained network baseline bandwidth
+	// for the instance type, in Mbit/s, as reported by DescribeInstanceTypes'
+	// NetworkCards[0].BaselineBandwidthInGbps. It is 0 for instance types
+	// AWS doesn't publish a baseline for; callers needing a peak figure in
+	// that case should derive one from NetworkPerformance instead.
+	BaselineBandwidthInMbps int
+
+	// MemoryInMiB is the default amount of memory for the instance type,
+	// in MiB.
+	MemoryInMiB int
+}
@@ -0,0 +1,292 @@
+package fingerprint
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	log "github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hashicorp/nomad/client/config"
+)
+
+// imdsv2TestServer is a minimal stand-in for the EC2 instance metadata
+// service that supports the IMDSv2 token handshake: PUT /latest/api/token
+// followed by GET /latest/meta-data/<path> with the token echoed back in
+// X-aws-ec2-metadata-token.
+func imdsv2TestServer(t *testing.T, metadata map[string]string) *httptest.Server {
+	t.Helper()
+
+	const validToken = "test-token"
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			if r.Header.Get(imdsTokenTTLHeader) == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(validToken))
+
+		case r.Method == http.MethodGet && len(r.URL.Path) > len("/latest/meta-data/"):
+			if r.Header.Get(imdsTokenAuthHeader) != validToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			p := r.URL.Path[len("/latest/meta-data/"):]
+			v, ok := metadata[p]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(v))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+// imdsv1OnlyTestServer mimics a host that hasn't enabled IMDSv2: the token
+// endpoint 404s, but plain unauthenticated GETs succeed.
+func imdsv1OnlyTestServer(t *testing.T, metadata map[string]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			w.WriteHeader(http.StatusNotFound)
+
+		case r.Method == http.MethodGet && len(r.URL.Path) > len("/latest/meta-data/"):
+			p := r.URL.Path[len("/latest/meta-data/"):]
+			v, ok := metadata[p]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte(v))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestEC2MetadataClient_IMDSv2Handshake(t *testing.T) {
+	srv := imdsv2TestServer(t, map[string]string{"instance-type": "m5.large"})
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeAuto, log.NewNullLogger())
+	require.NoError(t, err)
+
+	v, err := ec2meta.GetMetadata("instance-type")
+	require.NoError(t, err)
+	require.Equal(t, "m5.large", v)
+}
+
+func TestEC2MetadataClient_AutoFallsBackToV1(t *testing.T) {
+	srv := imdsv1OnlyTestServer(t, map[string]string{"instance-type": "m5.large"})
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeAuto, log.NewNullLogger())
+	require.NoError(t, err)
+
+	v, err := ec2meta.GetMetadata("instance-type")
+	require.NoError(t, err)
+	require.Equal(t, "m5.large", v)
+	require.True(t, ec2meta.usingV1Fallback())
+}
+
+func TestEC2MetadataClient_TransientTokenFailureDoesNotStickyDisableV2(t *testing.T) {
+	var tokenRequests int
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			tokenRequests++
+			if tokenRequests == 1 {
+				// Simulate a transient failure, e.g. throttling, that is
+				// neither a 403 nor a 404.
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("test-token"))
+
+		case r.Method == http.MethodGet && r.URL.Path == "/latest/meta-data/instance-type":
+			// A real IMDSv1-capable host would serve this whether or not a
+			// token header is present.
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("m5.large"))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeAuto, log.NewNullLogger())
+	require.NoError(t, err)
+
+	// The first call hits the transient 503 on the token endpoint and falls
+	// back to v1 for that one request, but must not sticky-disable v2.
+	v, err := ec2meta.GetMetadata("instance-type")
+	require.NoError(t, err)
+	require.Equal(t, "m5.large", v)
+	require.False(t, ec2meta.usingV1Fallback())
+
+	// The second call should retry the token endpoint, succeed, and use v2.
+	v, err = ec2meta.GetMetadata("instance-type")
+	require.NoError(t, err)
+	require.Equal(t, "m5.large", v)
+	require.False(t, ec2meta.usingV1Fallback())
+	require.Equal(t, 2, tokenRequests)
+}
+
+func TestEC2MetadataClient_ForcedV2DoesNotFallBack(t *testing.T) {
+	srv := imdsv1OnlyTestServer(t, map[string]string{"instance-type": "m5.large"})
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeV2, log.NewNullLogger())
+	require.NoError(t, err)
+
+	_, err = ec2meta.GetMetadata("instance-type")
+	require.Error(t, err)
+}
+
+func TestEC2MetadataClient_ForcedV1SkipsTokenHandshake(t *testing.T) {
+	var sawTokenRequest bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/latest/api/token" {
+			sawTokenRequest = true
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("unused"))
+			return
+		}
+		if r.URL.Path == "/latest/meta-data/instance-type" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("m5.large"))
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeV1, log.NewNullLogger())
+	require.NoError(t, err)
+
+	v, err := ec2meta.GetMetadata("instance-type")
+	require.NoError(t, err)
+	require.Equal(t, "m5.large", v)
+	require.False(t, sawTokenRequest)
+}
+
+func TestEC2MetadataClient_RefreshesTokenOn401(t *testing.T) {
+	var tokenRequests int
+	const staleToken = "stale-token"
+	const freshToken = "fresh-token"
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			tokenRequests++
+			w.WriteHeader(http.StatusOK)
+			if tokenRequests == 1 {
+				_, _ = w.Write([]byte(staleToken))
+			} else {
+				_, _ = w.Write([]byte(freshToken))
+			}
+
+		case r.Method == http.MethodGet && r.URL.Path == "/latest/meta-data/instance-type":
+			if r.Header.Get(imdsTokenAuthHeader) != freshToken {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("m5.large"))
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeAuto, log.NewNullLogger())
+	require.NoError(t, err)
+
+	v, err := ec2meta.GetMetadata("instance-type")
+	require.NoError(t, err)
+	require.Equal(t, "m5.large", v)
+	require.Equal(t, 2, tokenRequests)
+}
+
+func TestIsAWS(t *testing.T) {
+	srv := imdsv2TestServer(t, map[string]string{"ami-id": "ami-1234"})
+	defer srv.Close()
+
+	ec2meta, err := newEC2MetaClient(srv.URL+"/latest", AwsMetadataTimeout, imdsModeAuto, log.NewNullLogger())
+	require.NoError(t, err)
+	require.True(t, isAWS(ec2meta))
+}
+
+func TestParseIMDSMode(t *testing.T) {
+	cases := map[string]imdsMode{
+		"v1":     imdsModeV1,
+		"V1":     imdsModeV1,
+		"v2":     imdsModeV2,
+		"auto":   imdsModeAuto,
+		"":       imdsModeAuto,
+		"bogus":  imdsModeAuto,
+		" v2 \t": imdsModeV2,
+	}
+
+	for in, want := range cases {
+		require.Equal(t, want, parseIMDSMode(in), "input %q", in)
+	}
+}
+
+// TestFingerprint_IMDSVersionConfigForcesV2 ensures the fingerprint.aws.imds_version
+// config option actually reaches the metadata client through Fingerprint,
+// and that a cached client is rebuilt if the configured mode changes between
+// calls rather than silently keeping the mode from the first call.
+func TestFingerprint_IMDSVersionConfigForcesV2(t *testing.T) {
+	srv := imdsv1OnlyTestServer(t, map[string]string{
+		"ami-id":        "ami-1234",
+		"instance-type": "m5.large",
+	})
+	defer srv.Close()
+
+	f := &EnvAWSFingerprint{
+		logger:   log.NewNullLogger(),
+		endpoint: srv.URL + "/latest",
+	}
+
+	// First call with "auto": the host is IMDSv1-only, so auto falls back
+	// and detects the instance normally.
+	autoRequest := &FingerprintRequest{
+		Config: &config.Config{
+			Options: map[string]string{AwsIMDSVersionConfig: string(imdsModeAuto)},
+		},
+	}
+	autoResponse := &FingerprintResponse{}
+	require.NoError(t, f.Fingerprint(autoRequest, autoResponse))
+	require.True(t, autoResponse.Detected)
+
+	// Second call forces "v2" against the same (v1-only) host. If the
+	// cached client from the first call were reused as-is, this would
+	// incorrectly keep behaving like "auto" and still detect the host.
+	// Forcing v2 against a host with no token endpoint must fail to
+	// authenticate and so must not detect AWS.
+	v2Request := &FingerprintRequest{
+		Config: &config.Config{
+			Options: map[string]string{AwsIMDSVersionConfig: string(imdsModeV2)},
+		},
+	}
+	v2Response := &FingerprintResponse{}
+	require.NoError(t, f.Fingerprint(v2Request, v2Response))
+	require.False(t, v2Response.Detected)
+}
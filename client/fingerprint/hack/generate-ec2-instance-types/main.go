@@ -0,0 +1,153 @@
+// Command generate-ec2-instance-types authenticates to EC2 and pages through
+// DescribeInstanceTypes, emitting a generated Go source file containing a
+// map[string]ec2InstanceInfo keyed by instance type. It is intended to be
+// run via `go generate` from client/fingerprint:
+//
+//	AWS_REGION=us-east-1 go generate ./client/fingerprint/...
+//
+// The output path is the command's first positional argument. Output is
+// deterministic: keys are sorted and the result is passed through gofmt
+// before being written, so re-running the generator against an unchanged
+// instance-type catalog produces a byte-identical file.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"sort"
+	"strconv"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/ec2"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s <output-file>\n", os.Args[0])
+		os.Exit(1)
+	}
+	outputPath := os.Args[1]
+
+	if err := run(outputPath); err != nil {
+		fmt.Fprintf(os.Stderr, "generate-ec2-instance-types: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(outputPath string) error {
+	table, err := fetchInstanceTypeTable()
+	if err != nil {
+		return fmt.Errorf("failed to fetch instance types: %w", err)
+	}
+
+	src, err := renderTable(table)
+	if err != nil {
+		return fmt.Errorf("failed to render output: %w", err)
+	}
+
+	return os.WriteFile(outputPath, src, 0644)
+}
+
+// instanceTypeEntry is a single row of the generated table, kept alongside
+// its instance type name so the rows can be sorted before rendering.
+type instanceTypeEntry struct {
+	Name                     string
+	SustainedClockSpeedInGhz float64
+	DefaultVCpus             int64
+	DefaultCores             int64
+	NetworkPerformance       string
+	BaselineBandwidthInMbps  int64
+	MemoryInMiB              int64
+}
+
+// SustainedClockSpeedGhzLiteral renders SustainedClockSpeedInGhz the same
+// way every time, so re-running the generator against an unchanged
+// instance-type catalog reproduces this file byte-for-byte. text/template's
+// default float formatting matches fmt's %v, which is already shortest-form,
+// but spelling it out here keeps that guarantee explicit rather than
+// incidental.
+func (e instanceTypeEntry) SustainedClockSpeedGhzLiteral() string {
+	return strconv.FormatFloat(e.SustainedClockSpeedInGhz, 'f', -1, 64)
+}
+
+func fetchInstanceTypeTable() ([]instanceTypeEntry, error) {
+	sess, err := session.NewSession(aws.NewConfig())
+	if err != nil {
+		return nil, err
+	}
+	svc := ec2.New(sess)
+
+	var entries []instanceTypeEntry
+	err = svc.DescribeInstanceTypesPages(&ec2.DescribeInstanceTypesInput{}, func(page *ec2.DescribeInstanceTypesOutput, lastPage bool) bool {
+		for _, it := range page.InstanceTypes {
+			entries = append(entries, toEntry(it))
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+	return entries, nil
+}
+
+func toEntry(it *ec2.InstanceTypeInfo) instanceTypeEntry {
+	entry := instanceTypeEntry{
+		Name: aws.StringValue(it.InstanceType),
+	}
+
+	if info := it.ProcessorInfo; info != nil {
+		entry.SustainedClockSpeedInGhz = aws.Float64Value(info.SustainedClockSpeedInGhz)
+	}
+	if info := it.VCpuInfo; info != nil {
+		entry.DefaultVCpus = aws.Int64Value(info.DefaultVCpus)
+		entry.DefaultCores = aws.Int64Value(info.DefaultCores)
+	}
+	if info := it.NetworkInfo; info != nil {
+		entry.NetworkPerformance = aws.StringValue(info.NetworkPerformance)
+		if cards := info.NetworkCards; len(cards) > 0 && cards[0].BaselineBandwidthInGbps != nil {
+			entry.BaselineBandwidthInMbps = int64(aws.Float64Value(cards[0].BaselineBandwidthInGbps) * 1000)
+		}
+	}
+	if info := it.MemoryInfo; info != nil {
+		entry.MemoryInMiB = aws.Int64Value(info.SizeInMiB)
+	}
+
+	return entry
+}
+
+var outputTemplate = template.Must(template.New("table").Parse(`// Code generated by client/fingerprint/hack/generate-ec2-instance-types; DO NOT EDIT.
+//
+// To regenerate, run:
+//
+//	AWS_REGION=us-east-1 go generate ./client/fingerprint/...
+
+package fingerprint
+
+var ec2InstanceTypeTable = map[string]ec2InstanceInfo{
+{{- range . }}
+	"{{ .Name }}": {
+		SustainedClockSpeedInGhz: {{ .SustainedClockSpeedGhzLiteral }},
+		DefaultVCpus:             {{ .DefaultVCpus }},
+		DefaultCores:             {{ .DefaultCores }},
+		NetworkPerformance:       "{{ .NetworkPerformance }}",
+		BaselineBandwidthInMbps:  {{ .BaselineBandwidthInMbps }},
+		MemoryInMiB:              {{ .MemoryInMiB }},
+	},
+{{- end }}
+}
+`))
+
+func renderTable(entries []instanceTypeEntry) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := outputTemplate.Execute(&buf, entries); err != nil {
+		return nil, err
+	}
+
+	return format.Source(buf.Bytes())
+}